@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lruCache is a fixed-capacity, least-recently-used cache safe for concurrent use. It tracks its own hit/miss
+// counters so callers can report a hit rate without keeping a separate set of counters.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	list     *list.List
+	items    map[interface{}]*list.Element
+
+	hits   uint64
+	misses uint64
+}
+
+type lruEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		list:     list.New(),
+		items:    make(map[interface{}]*list.Element),
+	}
+}
+
+// Get returns the cached value for key, moving it to the front of the eviction order on a hit.
+func (c *lruCache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	c.list.MoveToFront(element)
+	atomic.AddUint64(&c.hits, 1)
+
+	return element.Value.(*lruEntry).value, true
+}
+
+// Set inserts or updates the cached value for key, evicting the least-recently-used entry if the cache is at
+// capacity.
+func (c *lruCache) Set(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruEntry).value = value
+		c.list.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.list.PushFront(&lruEntry{key: key, value: value})
+
+	if c.list.Len() > c.capacity {
+		oldest := c.list.Back()
+		if oldest != nil {
+			c.list.Remove(oldest)
+			delete(c.items, oldest.Value.(*lruEntry).key)
+		}
+	}
+}
+
+// HitRate returns the fraction of Get calls that found a cached value, since the cache was created.
+func (c *lruCache) HitRate() float64 {
+	hits := atomic.LoadUint64(&c.hits)
+	misses := atomic.LoadUint64(&c.misses)
+
+	if hits+misses == 0 {
+		return 0
+	}
+
+	return float64(hits) / float64(hits+misses)
+}
+
+// xportCacheKey identifies the cached result of exporting a single RRD dataset over a time range.
+type xportCacheKey struct {
+	FilePath string
+	Dataset  string
+	CF       string
+	Start    time.Time
+	End      time.Time
+	Step     time.Duration
+}
+
+// hash returns a stable fingerprint of query's shape (name, type, scale and series), suitable for use as part
+// of an infoCacheKey.
+func (query *GroupQuery) hash() uint64 {
+	h := fnv.New64a()
+
+	fmt.Fprintf(h, "%s|%d|%f", query.Name, query.Type, query.Scale)
+
+	for _, serie := range query.Series {
+		if serie.Metric == nil {
+			fmt.Fprintf(h, "|%s,%f,-", serie.Name, serie.Scale)
+			continue
+		}
+
+		fmt.Fprintf(h, "|%s,%f,%s/%s", serie.Name, serie.Scale, serie.Metric.source.Name, serie.Metric.OriginalName)
+	}
+
+	return h.Sum64()
+}
+
+// infoCacheKey identifies the cached graph-information (percentile/min/max/avg) result of a GroupQuery, rounding
+// endTime down to step so requests issued moments apart against an unchanged window still share an entry. Step
+// is part of the key (not just a rounding granularity) so that two requests against the same query and the same
+// truncated end time but different windows — e.g. a history panel's wide GetPlots and a value widget's 1-minute
+// GetValue landing on the same round-minute boundary — never collide on the same entry.
+type infoCacheKey struct {
+	QueryHash uint64
+	StartTime time.Time
+	EndTime   time.Time
+	Step      time.Duration
+}
+
+// Stats reports query-cache effectiveness and latency for a connector.
+type Stats struct {
+	XportHitRate float64
+	InfoHitRate  float64
+	QueryCount   uint64
+	AvgLatency   time.Duration
+}
+
+// StatsReporter is implemented by connectors that track query-cache effectiveness and latency. Callers should
+// type-assert a ConnectorHandler against it, since not every connector has a query-plan cache to report on (e.g.
+// Graphite doesn't; Collectd does, through its embedded RRDConnectorHandler).
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// latencyTracker accumulates query count and total latency so an average can be reported without keeping every
+// individual sample around.
+type latencyTracker struct {
+	count uint64
+	total time.Duration
+	mu    sync.Mutex
+}
+
+func (t *latencyTracker) Record(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.count++
+	t.total += d
+}
+
+func (t *latencyTracker) Average() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.count == 0 {
+		return 0
+	}
+
+	return t.total / time.Duration(t.count)
+}
+
+func (t *latencyTracker) Count() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.count
+}
@@ -0,0 +1,81 @@
+package connector
+
+import (
+	"time"
+
+	"github.com/facette/facette/pkg/types"
+)
+
+// ConnectorHandler represents the interface that every catalog connector backend must implement.
+type ConnectorHandler interface {
+	// GetPlots calculates and returns plot data based on a time interval.
+	GetPlots(query *GroupQuery, startTime, endTime time.Time, step time.Duration,
+		percentiles []float64) (map[string]*PlotResult, error)
+	// GetValue calculates and returns plot data at a specific reference time.
+	GetValue(query *GroupQuery, refTime time.Time,
+		percentiles []float64) (map[string]map[string]types.PlotValue, error)
+	// Update triggers a full connector data update.
+	Update() error
+	// Refresh triggers an incremental connector data update.
+	Refresh() error
+	// Close releases any resource held by the connector.
+	Close() error
+}
+
+// ConnectorHandlers stores the registered connector handler factories, indexed by their `type' configuration key.
+var ConnectorHandlers = make(map[string]func(origin *Origin, config map[string]string) error)
+
+// Origin represents the main structure of a catalog origin, binding a registered connector to the metrics it
+// provides.
+type Origin struct {
+	Name      string
+	Connector ConnectorHandler
+	// Rollup holds the origin's pre-aggregated rollup storage, when its connector was configured with one. It
+	// is nil otherwise, and callers must check before use.
+	Rollup    *RollupStore
+	inputChan chan [2]string
+	deltaChan chan [2]string
+}
+
+// NewOrigin creates a new Origin instance, ready to be handed to a ConnectorHandler factory.
+func NewOrigin(name string) *Origin {
+	return &Origin{
+		Name:      name,
+		inputChan: make(chan [2]string),
+		deltaChan: make(chan [2]string, 256),
+	}
+}
+
+// PushMetric reports a single source/metric discovery outside of a full Update, e.g. from a file-system
+// watcher. Unlike the channel used by Update, this never gets closed, so connectors may call it for as long as
+// the origin is alive.
+func (origin *Origin) PushMetric(source, metric string) {
+	origin.deltaChan <- [2]string{source, metric}
+}
+
+// Deltas returns the channel incremental source/metric discoveries are streamed on.
+func (origin *Origin) Deltas() <-chan [2]string {
+	return origin.deltaChan
+}
+
+// Discoveries returns the channel a full Update streams its source/metric pairs on. It is closed once the
+// triggering Update call returns.
+func (origin *Origin) Discoveries() <-chan [2]string {
+	return origin.inputChan
+}
+
+// PlotResult represents the main structure of a plots result, shared by every connector backend.
+type PlotResult struct {
+	Plots []types.PlotValue
+	Info  map[string]types.PlotValue
+}
+
+// PlotQuery normalizes the arguments of a GetPlots call into a single value, so a QueryPlan can queue and batch
+// plot requests without consumers juggling the argument list themselves.
+type PlotQuery struct {
+	Group       *GroupQuery
+	StartTime   time.Time
+	EndTime     time.Time
+	Step        time.Duration
+	Percentiles []float64
+}
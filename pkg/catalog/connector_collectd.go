@@ -0,0 +1,125 @@
+package connector
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/facette/facette/thirdparty/github.com/ziutek/rrd"
+)
+
+// CollectdConnectorHandler represents the main structure of the Collectd connector.
+//
+// It walks a directory laid out the way `collectd's `rrdtool' plugin writes it
+// (`<path>/<host>/<plugin>[-<instance>]/<type>[-<instance>].rrd') and otherwise relies on the RRD connector
+// for graphing and exporting, since the underlying storage is plain RRD files.
+//
+// It supplies its own Refresh and file-system watch wiring rather than inheriting RRDConnectorHandler's, since
+// those rely on matching handler.Pattern against each path via a regexp with named `source'/`metric' groups,
+// which collectd's fixed host/plugin/type layout has no use for.
+type CollectdConnectorHandler struct {
+	*RRDConnectorHandler
+}
+
+// walkFile returns a filepath.WalkFunc that registers the source/metric pairs found under handler.Path, laid
+// out the way collectd's `rrdtool' plugin writes it. When bulk is true, every matched file is parsed and pushed
+// onto the connector's initial input channel; otherwise only files whose mtime advanced past their recorded
+// lastSeen entry are re-parsed, and discoveries are pushed as deltas instead so the origin isn't rebuilt from
+// scratch.
+func (handler *CollectdConnectorHandler) walkFile(bulk bool) func(string, os.FileInfo, error) error {
+	return func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		mode := fileInfo.Mode() & os.ModeType
+		if mode != 0 || !strings.HasSuffix(filePath, ".rrd") {
+			return nil
+		}
+
+		if !bulk {
+			handler.lastMu.Lock()
+			seen, ok := handler.lastSeen[filePath]
+			handler.lastMu.Unlock()
+
+			if ok && !fileInfo.ModTime().After(seen) {
+				return nil
+			}
+		}
+
+		relPath := filePath[len(handler.Path)+1:]
+		chunks := strings.Split(relPath, string(filepath.Separator))
+		if len(chunks) != 3 {
+			log.Printf("WARNING: file `%s' does not match the collectd source/plugin/type layout", filePath)
+			return nil
+		}
+
+		source := chunks[0]
+		metric := chunks[1] + "/" + strings.TrimSuffix(chunks[2], ".rrd")
+
+		info, err := rrd.Info(filePath)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := info["ds.index"]; ok {
+			for dsName := range info["ds.index"].(map[string]interface{}) {
+				metricName := metric + "/" + dsName
+
+				if bulk {
+					handler.origin.inputChan <- [2]string{source, metricName}
+				} else {
+					handler.origin.PushMetric(source, metricName)
+				}
+
+				handler.setMetric(source, metricName, &RRDMetric{Dataset: dsName, FilePath: filePath})
+			}
+		}
+
+		handler.lastMu.Lock()
+		handler.lastSeen[filePath] = fileInfo.ModTime()
+		handler.lastMu.Unlock()
+
+		return nil
+	}
+}
+
+// Update triggers a full connector data update, walking the Collectd hierarchy, then starts watching
+// handler.Path for new `.rrd' files so they register without waiting for the next scheduled refresh.
+func (handler *CollectdConnectorHandler) Update() error {
+	handler.lastSeen = make(map[string]time.Time)
+
+	if err := filepath.Walk(handler.Path, handler.walkFile(true)); err != nil {
+		return err
+	}
+
+	close(handler.origin.inputChan)
+
+	return handler.watch(handler.walkFile(false))
+}
+
+// Refresh triggers an incremental connector data update, only inspecting files whose modification time is newer
+// than the last time they were seen.
+func (handler *CollectdConnectorHandler) Refresh() error {
+	return filepath.Walk(handler.Path, handler.walkFile(false))
+}
+
+func init() {
+	ConnectorHandlers["collectd"] = func(origin *Origin, config map[string]string) error {
+		if _, ok := config["path"]; !ok {
+			return fmt.Errorf("missing `path' mandatory connector setting")
+		}
+
+		handler, err := newRRDConnectorHandler(origin, config)
+		if err != nil {
+			return err
+		}
+
+		origin.Connector = &CollectdConnectorHandler{RRDConnectorHandler: handler}
+
+		return nil
+	}
+}
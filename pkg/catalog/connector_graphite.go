@@ -0,0 +1,243 @@
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/facette/facette/pkg/types"
+)
+
+// GraphiteConnectorHandler represents the main structure of the Graphite connector.
+type GraphiteConnectorHandler struct {
+	URL    string
+	origin *Origin
+	client *http.Client
+
+	metrics map[string]map[string]string
+	// metricsMu guards metrics, written by both Update and the periodic Refresh ticker while concurrently read
+	// by every in-flight GetPlots/GetValue call.
+	metricsMu sync.RWMutex
+}
+
+// getMetric returns the Graphite target registered for source/name, safe for concurrent use against Refresh.
+func (handler *GraphiteConnectorHandler) getMetric(source, name string) (string, bool) {
+	handler.metricsMu.RLock()
+	defer handler.metricsMu.RUnlock()
+
+	target, ok := handler.metrics[source][name]
+
+	return target, ok
+}
+
+// setMetric registers target for source/name, creating the source entry as needed. Safe for concurrent use.
+func (handler *GraphiteConnectorHandler) setMetric(source, name, target string) {
+	handler.metricsMu.Lock()
+	defer handler.metricsMu.Unlock()
+
+	if _, ok := handler.metrics[source]; !ok {
+		handler.metrics[source] = make(map[string]string)
+	}
+
+	handler.metrics[source][name] = target
+}
+
+// graphiteSerie represents a single entry of a Graphite `/render?format=json' response.
+type graphiteSerie struct {
+	Target     string        `json:"target"`
+	DataPoints [][2]*float64 `json:"datapoints"`
+}
+
+// GetPlots calculates and returns plot data based on a time interval.
+func (handler *GraphiteConnectorHandler) GetPlots(query *GroupQuery, startTime, endTime time.Time,
+	step time.Duration, percentiles []float64) (map[string]*PlotResult, error) {
+
+	result := make(map[string]*PlotResult)
+
+	for _, serie := range query.Series {
+		if serie.Metric == nil {
+			continue
+		}
+
+		target, ok := handler.getMetric(serie.Metric.source.Name, serie.Metric.OriginalName)
+		if !ok {
+			return nil, fmt.Errorf("unknown metric `%s'", serie.Metric.OriginalName)
+		}
+
+		series, err := handler.render(target, startTime, endTime)
+		if err != nil {
+			return nil, err
+		}
+
+		plotResult := &PlotResult{Info: make(map[string]types.PlotValue)}
+
+		for _, point := range series {
+			if point[0] == nil {
+				plotResult.Plots = append(plotResult.Plots, types.PlotValue(math.NaN()))
+			} else {
+				plotResult.Plots = append(plotResult.Plots, types.PlotValue(*point[0]))
+			}
+		}
+
+		result[serie.Name] = plotResult
+	}
+
+	return result, nil
+}
+
+// GetValue returns, for each serie, the Info map of a GetPlots call over the minute before refTime. GetPlots
+// leaves Info empty, so no min/avg/max/last or percentile values are returned.
+func (handler *GraphiteConnectorHandler) GetValue(query *GroupQuery, refTime time.Time,
+	percentiles []float64) (map[string]map[string]types.PlotValue, error) {
+
+	result := make(map[string]map[string]types.PlotValue)
+
+	data, err := handler.GetPlots(query, refTime.Add(-1*time.Minute), refTime, time.Minute, percentiles)
+	if err != nil {
+		return nil, err
+	}
+
+	for serieName := range data {
+		result[serieName] = data[serieName].Info
+	}
+
+	return result, nil
+}
+
+// fetchIndex retrieves Graphite's flat list of known metric paths.
+func (handler *GraphiteConnectorHandler) fetchIndex() ([]string, error) {
+	resp, err := handler.client.Get(strings.TrimRight(handler.URL, "/") + "/metrics/index.json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received HTTP %d from Graphite index", resp.StatusCode)
+	}
+
+	var index []string
+
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// Update triggers a full connector data update, fetching the metrics index from Graphite.
+func (handler *GraphiteConnectorHandler) Update() error {
+	index, err := handler.fetchIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, target := range index {
+		// Graphite paths use `source.metric.parts' dotted notation; use the first component as the source
+		// and the remainder as the metric name, mirroring the RRD connector's source/metric split.
+		chunks := strings.SplitN(target, ".", 2)
+		if len(chunks) != 2 {
+			continue
+		}
+
+		source, metric := chunks[0], chunks[1]
+
+		handler.setMetric(source, metric, target)
+		handler.origin.inputChan <- [2]string{source, metric}
+	}
+
+	close(handler.origin.inputChan)
+
+	return nil
+}
+
+// Refresh triggers an incremental connector data update.
+//
+// Graphite's index endpoint is cheap to re-fetch and already reflects newly ingested series, but inputChan is
+// only ever read by the single Discoveries loop driving the initial Update, and Update closes it once the index
+// has been sent, which ends that loop (see origin.Update in src/facette/backend/origin.go) — re-sending on it here would block forever with no
+// reader, or panic on an already-closed channel. So Refresh re-fetches the index itself and reports only metrics
+// not already known through origin.PushMetric/deltaChan, the same incremental path the RRD connector's watcher
+// uses.
+func (handler *GraphiteConnectorHandler) Refresh() error {
+	index, err := handler.fetchIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, target := range index {
+		chunks := strings.SplitN(target, ".", 2)
+		if len(chunks) != 2 {
+			continue
+		}
+
+		source, metric := chunks[0], chunks[1]
+
+		if _, ok := handler.getMetric(source, metric); ok {
+			continue
+		}
+
+		handler.setMetric(source, metric, target)
+		handler.origin.PushMetric(source, metric)
+	}
+
+	return nil
+}
+
+// Close releases any resource held by the connector.
+func (handler *GraphiteConnectorHandler) Close() error {
+	return nil
+}
+
+func (handler *GraphiteConnectorHandler) render(target string, startTime, endTime time.Time) ([][2]*float64, error) {
+	query := url.Values{}
+	query.Set("target", target)
+	query.Set("format", "json")
+	query.Set("from", strconv.FormatInt(startTime.Unix(), 10))
+	query.Set("until", strconv.FormatInt(endTime.Unix(), 10))
+
+	resp, err := handler.client.Get(strings.TrimRight(handler.URL, "/") + "/render?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received HTTP %d from Graphite render endpoint", resp.StatusCode)
+	}
+
+	var series []graphiteSerie
+
+	if err := json.NewDecoder(resp.Body).Decode(&series); err != nil {
+		return nil, err
+	}
+
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no data returned for target `%s'", target)
+	}
+
+	return series[0].DataPoints, nil
+}
+
+func init() {
+	ConnectorHandlers["graphite"] = func(origin *Origin, config map[string]string) error {
+		if _, ok := config["url"]; !ok {
+			return fmt.Errorf("missing `url' mandatory connector setting")
+		}
+
+		origin.Connector = &GraphiteConnectorHandler{
+			URL:     config["url"],
+			origin:  origin,
+			metrics: make(map[string]map[string]string),
+			client:  &http.Client{Timeout: 30 * time.Second},
+		}
+
+		return nil
+	}
+}
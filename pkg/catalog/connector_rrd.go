@@ -8,34 +8,124 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/facette/facette/pkg/types"
 	"github.com/facette/facette/pkg/utils"
 	"github.com/facette/facette/thirdparty/github.com/ziutek/rrd"
+	"github.com/facette/facette/thirdparty/gopkg.in/fsnotify.v1"
 )
 
 // RRDConnectorHandler represents the main structure of the RRD connector.
 type RRDConnectorHandler struct {
 	Path    string
 	Pattern string
+	Daemon  string
 	origin  *Origin
 	metrics map[string]map[string]*RRDMetric
+	// metricsMu guards metrics, written by walkFile from both the periodic Refresh ticker and the fsnotify
+	// watcher goroutine, while concurrently read by every in-flight GetPlots/GetValue/GetPlotsBatch call.
+	metricsMu sync.RWMutex
+
+	re       *regexp.Regexp
+	lastSeen map[string]time.Time
+	lastMu   sync.Mutex
+	watcher  *fsnotify.Watcher
+	rollup   *RollupStore
+
+	xportCache *lruCache
+	infoCache  *lruCache
+	stackPool  sync.Pool
+	seriesPool sync.Pool
+	latency    *latencyTracker
 }
 
+// rrdCacheSize bounds the number of entries kept in each query-plan cache, trading memory for hit rate.
+const rrdCacheSize = 4096
+
 // RRDMetric represents the main structure of a RRD metric information.
 type RRDMetric struct {
 	Dataset  string
 	FilePath string
 }
 
+// getMetric returns the RRDMetric registered for source/name, safe for concurrent use against walkFile (invoked
+// from both the Refresh ticker and the fsnotify watcher goroutine).
+func (handler *RRDConnectorHandler) getMetric(source, name string) (*RRDMetric, bool) {
+	handler.metricsMu.RLock()
+	defer handler.metricsMu.RUnlock()
+
+	metric, ok := handler.metrics[source][name]
+
+	return metric, ok
+}
+
+// setMetric registers metric for source/name, creating the source entry as needed. Safe for concurrent use.
+func (handler *RRDConnectorHandler) setMetric(source, name string, metric *RRDMetric) {
+	handler.metricsMu.Lock()
+	defer handler.metricsMu.Unlock()
+
+	if _, ok := handler.metrics[source]; !ok {
+		handler.metrics[source] = make(map[string]*RRDMetric)
+	}
+
+	handler.metrics[source][name] = metric
+}
+
 // GetPlots calculates and returns plot data based on a time interval.
 func (handler *RRDConnectorHandler) GetPlots(query *GroupQuery, startTime, endTime time.Time, step time.Duration,
 	percentiles []float64) (map[string]*PlotResult, error) {
 
+	if result, ok := handler.rollupGetPlots(query, startTime, endTime, step); ok {
+		return result, nil
+	}
+
 	return handler.rrdGetData(query, startTime, endTime, step, percentiles, false)
 }
 
+// rollupGetPlots transparently serves query from the rollup store when one is configured and every one of its
+// series has a ring buffer at exactly step's resolution, sparing a round-trip through RRD xport/graph for the
+// common case of a dashboard panel plotting raw, unaggregated metrics. The second return value reports whether
+// the rollup store was able to serve the whole query.
+func (handler *RRDConnectorHandler) rollupGetPlots(query *GroupQuery, startTime, endTime time.Time,
+	step time.Duration) (map[string]*PlotResult, bool) {
+
+	// The rollup store only ever records raw, unscaled samples (see recordRollup), so a query that applies a
+	// scale factor — at the group or the serie level — can't be served from it without silently dropping that
+	// scale; fall back to RRD xport/graph, which apply both via CDef.
+	if handler.rollup == nil || query.Type != OperGroupTypeNone || query.Scale != 0 {
+		return nil, false
+	}
+
+	result := make(map[string]*PlotResult)
+
+	for _, serie := range query.Series {
+		if serie.Metric == nil {
+			continue
+		}
+
+		if serie.Scale != 0 {
+			return nil, false
+		}
+
+		source, metric := serie.Metric.source.Name, serie.Metric.OriginalName
+
+		if !handler.rollup.HasResolution(source, metric, step) {
+			return nil, false
+		}
+
+		plots, err := handler.rollup.Query(source, metric, RollupAvg, startTime, endTime, step)
+		if err != nil {
+			return nil, false
+		}
+
+		result[serie.Name] = &PlotResult{Plots: plots, Info: make(map[string]types.PlotValue)}
+	}
+
+	return result, true
+}
+
 // GetValue calculates and returns plot data at a specific reference time.
 func (handler *RRDConnectorHandler) GetValue(query *GroupQuery, refTime time.Time,
 	percentiles []float64) (map[string]map[string]types.PlotValue, error) {
@@ -49,17 +139,94 @@ func (handler *RRDConnectorHandler) GetValue(query *GroupQuery, refTime time.Tim
 
 	for serieName := range data {
 		result[serieName] = data[serieName].Info
+
+		if handler.rollup != nil {
+			handler.recordRollup(query, serieName, refTime, data[serieName].Info)
+		}
 	}
 
 	return result, err
 }
 
-// Update triggers a full connector data update.
+// recordRollup feeds the current sampled value of each metric in query into the rollup store, keyed by the
+// serie name GetValue resolved it to.
+func (handler *RRDConnectorHandler) recordRollup(query *GroupQuery, serieName string, refTime time.Time,
+	info map[string]types.PlotValue) {
+
+	last, ok := info["last"]
+	if !ok || query.Type != OperGroupTypeNone || query.Scale != 0 {
+		return
+	}
+
+	for _, serie := range query.Series {
+		if serie.Metric == nil || serie.Name != serieName || serie.Scale != 0 {
+			continue
+		}
+
+		handler.rollup.Record(serie.Metric.source.Name, serie.Metric.OriginalName, refTime, float64(last))
+	}
+}
+
+// Close releases any resource held by the connector, stopping the file-system watcher and rollup flush loop if
+// either was started.
+func (handler *RRDConnectorHandler) Close() error {
+	if handler.rollup != nil {
+		handler.rollup.Stop()
+	}
+
+	if handler.watcher != nil {
+		return handler.watcher.Close()
+	}
+
+	return nil
+}
+
+// Stats reports the handler's query-cache hit rates and average query latency, implementing StatsReporter.
+func (handler *RRDConnectorHandler) Stats() Stats {
+	return Stats{
+		XportHitRate: handler.xportCache.HitRate(),
+		InfoHitRate:  handler.infoCache.HitRate(),
+		QueryCount:   handler.latency.Count(),
+		AvgLatency:   handler.latency.Average(),
+	}
+}
+
+// Update triggers a full connector data update, then starts watching handler.Path for new `.rrd' files so they
+// register without waiting for the next scheduled refresh.
 func (handler *RRDConnectorHandler) Update() error {
-	// Compile pattern
+	if err := handler.compilePattern(); err != nil {
+		return err
+	}
+
+	handler.lastSeen = make(map[string]time.Time)
+
+	if err := utils.WalkDir(handler.Path, handler.walkFile(true)); err != nil {
+		return err
+	}
+
+	close(handler.origin.inputChan)
+
+	return handler.watch(handler.walkFile(false))
+}
+
+// Refresh triggers an incremental connector data update, only inspecting files whose modification time is
+// newer than the last time they were seen.
+func (handler *RRDConnectorHandler) Refresh() error {
+	if err := handler.compilePattern(); err != nil {
+		return err
+	}
+
+	return utils.WalkDir(handler.Path, handler.walkFile(false))
+}
+
+// compilePattern lazily compiles and validates handler.Pattern.
+func (handler *RRDConnectorHandler) compilePattern() error {
+	if handler.re != nil {
+		return nil
+	}
+
 	re := regexp.MustCompile(handler.Pattern)
 
-	// Validate pattern keywords
 	groups := make(map[string]bool)
 
 	for _, key := range re.SubexpNames() {
@@ -78,8 +245,17 @@ func (handler *RRDConnectorHandler) Update() error {
 		return fmt.Errorf("missing pattern keyword `metric'")
 	}
 
-	// Search for files and parse their path for source/metric pairs
-	walkFunc := func(filePath string, fileInfo os.FileInfo, err error) error {
+	handler.re = re
+
+	return nil
+}
+
+// walkFile returns a filepath.WalkFunc that registers the source/metric pairs found under handler.Path. When
+// bulk is true, every matched file is parsed and pushed onto the connector's initial input channel; otherwise
+// only files whose mtime advanced past their recorded lastSeen entry are re-parsed, and discoveries are pushed
+// as deltas instead so the origin isn't rebuilt from scratch.
+func (handler *RRDConnectorHandler) walkFile(bulk bool) func(string, os.FileInfo, error) error {
+	return func(filePath string, fileInfo os.FileInfo, err error) error {
 		var (
 			metric string
 			source string
@@ -96,13 +272,23 @@ func (handler *RRDConnectorHandler) Update() error {
 			return nil
 		}
 
-		submatch := re.FindStringSubmatch(filePath[len(handler.Path)+1:])
+		if !bulk {
+			handler.lastMu.Lock()
+			seen, ok := handler.lastSeen[filePath]
+			handler.lastMu.Unlock()
+
+			if ok && !fileInfo.ModTime().After(seen) {
+				return nil
+			}
+		}
+
+		submatch := handler.re.FindStringSubmatch(filePath[len(handler.Path)+1:])
 		if len(submatch) == 0 {
 			log.Printf("WARNING: file `%s' does not match pattern", filePath)
 			return nil
 		}
 
-		if re.SubexpNames()[1] == "source" {
+		if handler.re.SubexpNames()[1] == "source" {
 			source = submatch[1]
 			metric = submatch[2]
 		} else {
@@ -110,10 +296,6 @@ func (handler *RRDConnectorHandler) Update() error {
 			metric = submatch[1]
 		}
 
-		if _, ok := handler.metrics[source]; !ok {
-			handler.metrics[source] = make(map[string]*RRDMetric)
-		}
-
 		// Extract metric information from .rrd file
 		info, err := rrd.Info(filePath)
 		if err != nil {
@@ -124,28 +306,134 @@ func (handler *RRDConnectorHandler) Update() error {
 			for dsName := range info["ds.index"].(map[string]interface{}) {
 				metricName := metric + "/" + dsName
 
-				handler.origin.inputChan <- [2]string{source, metricName}
-				handler.metrics[source][metricName] = &RRDMetric{Dataset: dsName, FilePath: filePath}
+				if bulk {
+					handler.origin.inputChan <- [2]string{source, metricName}
+				} else {
+					handler.origin.PushMetric(source, metricName)
+				}
+
+				handler.setMetric(source, metricName, &RRDMetric{Dataset: dsName, FilePath: filePath})
 			}
 		}
 
-		return err
+		handler.lastMu.Lock()
+		handler.lastSeen[filePath] = fileInfo.ModTime()
+		handler.lastMu.Unlock()
+
+		return nil
 	}
+}
 
-	err := utils.WalkDir(handler.Path, walkFunc)
+// watch starts (once) an fsnotify watcher on handler.Path, invoking walkFn for every file created or written to
+// as soon as it appears. walkFn is a parameter (rather than always handler.walkFile(false)) so connectors built
+// atop RRDConnectorHandler with their own path layout — e.g. CollectdConnectorHandler — can reuse the watcher
+// plumbing while supplying their own path-to-source/metric parsing.
+func (handler *RRDConnectorHandler) watch(walkFn func(string, os.FileInfo, error) error) error {
+	if handler.watcher != nil {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return err
 	}
 
-	// Close channel once updated
-	close(handler.origin.inputChan)
+	if err := utils.WalkDir(handler.Path, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fileInfo.IsDir() {
+			return watcher.Add(filePath)
+		}
+
+		return nil
+	}); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	handler.watcher = watcher
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				// Events other than Create/Write (e.g. Chmod, Remove, Rename) don't need re-registering;
+				// skip just this event rather than killing the watcher for the rest of the process's life.
+				if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+					continue
+				}
+
+				info, err := os.Stat(event.Name)
+				if err != nil {
+					continue
+				}
+
+				// Watch newly created directories too, so files later written inside them get registered.
+				if info.IsDir() {
+					if err := watcher.Add(event.Name); err != nil {
+						log.Printf("WARNING: failed to watch `%s': %s", event.Name, err)
+					}
+
+					continue
+				}
+
+				if err := walkFn(event.Name, info, nil); err != nil {
+					log.Printf("WARNING: failed to register `%s': %s", event.Name, err)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Printf("WARNING: file-system watch error: %s", err)
+			}
+		}
+	}()
 
 	return nil
 }
 
+// rrdGetData serves a single group query, transparently caching its graph-information result (percentiles,
+// min/max/avg) by query shape and time window so repeated requests against an unchanged window — the common
+// case for a "current value" widget polling GetValue — skip the rrd.Grapher round-trip entirely.
 func (handler *RRDConnectorHandler) rrdGetData(query *GroupQuery, startTime, endTime time.Time, step time.Duration,
 	percentiles []float64, infoOnly bool) (map[string]*PlotResult, error) {
 
+	start := time.Now()
+	defer func() { handler.latency.Record(time.Since(start)) }()
+
+	cacheKey := infoCacheKey{
+		QueryHash: query.hash(),
+		StartTime: startTime.Truncate(step),
+		EndTime:   endTime.Truncate(step),
+		Step:      step,
+	}
+
+	if cached, ok := handler.infoCache.Get(cacheKey); ok {
+		if infoOnly {
+			return cached.(map[string]*PlotResult), nil
+		}
+	}
+
+	result, err := handler.rrdGetDataUncached(query, startTime, endTime, step, percentiles, infoOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	handler.infoCache.Set(cacheKey, result)
+
+	return result, nil
+}
+
+func (handler *RRDConnectorHandler) rrdGetDataUncached(query *GroupQuery, startTime, endTime time.Time,
+	step time.Duration, percentiles []float64, infoOnly bool) (map[string]*PlotResult, error) {
+
 	var xport *rrd.Exporter
 
 	if len(query.Series) == 0 {
@@ -155,15 +443,36 @@ func (handler *RRDConnectorHandler) rrdGetData(query *GroupQuery, startTime, end
 	}
 
 	result := make(map[string]*PlotResult)
-	series := make(map[string]string)
 
-	stack := []string{}
+	series := handler.seriesPool.Get().(map[string]string)
+	defer func() {
+		for k := range series {
+			delete(series, k)
+		}
+		handler.seriesPool.Put(series)
+	}()
+
+	stack := handler.stackPool.Get().([]string)[:0]
+	defer handler.stackPool.Put(stack)
+
+	// pendingXportKeys records, for series not already served from handler.xportCache, the key their freshly
+	// exported rows should be cached under once the Xport call returns.
+	pendingXportKeys := make(map[string]xportCacheKey)
+
 	graph := rrd.NewGrapher()
 
 	if !infoOnly {
 		xport = rrd.NewExporter()
 	}
 
+	if handler.Daemon != "" {
+		graph.SetDaemon(handler.Daemon)
+
+		if !infoOnly {
+			xport.SetDaemon(handler.Daemon)
+		}
+	}
+
 	count := 0
 
 	switch query.Type {
@@ -173,17 +482,17 @@ func (handler *RRDConnectorHandler) rrdGetData(query *GroupQuery, startTime, end
 				continue
 			}
 
+			metric, ok := handler.getMetric(serie.Metric.source.Name, serie.Metric.OriginalName)
+			if !ok {
+				continue
+			}
+
 			serieTemp := fmt.Sprintf("serie%d", count)
 			serieName := serie.Name
 
 			count += 1
 
-			graph.Def(
-				serieTemp+"-orig0",
-				handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].FilePath,
-				handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].Dataset,
-				"AVERAGE",
-			)
+			graph.Def(serieTemp+"-orig0", metric.FilePath, metric.Dataset, "AVERAGE")
 
 			if serie.Scale != 0 {
 				graph.CDef(serieTemp+"-orig1", fmt.Sprintf("%s-orig0,%f,*", serieTemp, serie.Scale))
@@ -200,32 +509,52 @@ func (handler *RRDConnectorHandler) rrdGetData(query *GroupQuery, startTime, end
 			// Set graph information request
 			rrdSetGraph(graph, serieTemp, serieName, percentiles)
 
-			// Set plots request
+			// Set plots request, skipping the xport Def/XportDef entirely when an unscaled serie's raw rows
+			// are already cached for this exact (file, dataset, cf, start, end, step) combination.
 			if !infoOnly {
-				xport.Def(
-					serieTemp+"-orig0",
-					handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].FilePath,
-					handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].Dataset,
-					"AVERAGE",
-				)
-
-				if serie.Scale != 0 {
-					xport.CDef(serieTemp+"-orig1", fmt.Sprintf("%s-orig0,%f,*", serieTemp, serie.Scale))
-				} else {
-					xport.CDef(serieTemp+"-orig1", serieTemp+"-orig0")
+				var xportKey xportCacheKey
+				cached := false
+
+				if serie.Scale == 0 && query.Scale == 0 {
+					xportKey = xportCacheKey{
+						FilePath: metric.FilePath, Dataset: metric.Dataset, CF: "AVERAGE",
+						Start: startTime, End: endTime, Step: step,
+					}
+
+					if rows, ok := handler.xportCache.Get(xportKey); ok {
+						result[serieName] = &PlotResult{Plots: rows.([]types.PlotValue), Info: make(map[string]types.PlotValue)}
+						cached = true
+					}
 				}
 
-				if query.Scale != 0 {
-					xport.CDef(serieTemp, fmt.Sprintf("%s-orig1,%f,*", serieTemp, query.Scale))
-				} else {
-					xport.CDef(serieTemp, serieTemp+"-orig1")
-				}
+				if !cached {
+					xport.Def(serieTemp+"-orig0", metric.FilePath, metric.Dataset, "AVERAGE")
 
-				xport.XportDef(serieTemp, serieTemp)
-			}
+					if serie.Scale != 0 {
+						xport.CDef(serieTemp+"-orig1", fmt.Sprintf("%s-orig0,%f,*", serieTemp, serie.Scale))
+					} else {
+						xport.CDef(serieTemp+"-orig1", serieTemp+"-orig0")
+					}
+
+					if query.Scale != 0 {
+						xport.CDef(serieTemp, fmt.Sprintf("%s-orig1,%f,*", serieTemp, query.Scale))
+					} else {
+						xport.CDef(serieTemp, serieTemp+"-orig1")
+					}
 
-			// Set serie matching
-			series[serieTemp] = serieName
+					xport.XportDef(serieTemp, serieTemp)
+
+					// Set serie matching, so the freshly exported row can both fill the result and be cached.
+					series[serieTemp] = serieName
+
+					if xportKey != (xportCacheKey{}) {
+						pendingXportKeys[serieName] = xportKey
+					}
+				}
+			} else {
+				// Set serie matching
+				series[serieTemp] = serieName
+			}
 		}
 
 		break
@@ -239,22 +568,17 @@ func (handler *RRDConnectorHandler) rrdGetData(query *GroupQuery, startTime, end
 				continue
 			}
 
+			metric, ok := handler.getMetric(serie.Metric.source.Name, serie.Metric.OriginalName)
+			if !ok {
+				continue
+			}
+
 			serieTemp := serieName + fmt.Sprintf("-tmp%d", index)
 
-			graph.Def(
-				serieTemp,
-				handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].FilePath,
-				handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].Dataset,
-				"AVERAGE",
-			)
+			graph.Def(serieTemp, metric.FilePath, metric.Dataset, "AVERAGE")
 
 			if !infoOnly {
-				xport.Def(
-					serieTemp,
-					handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].FilePath,
-					handler.metrics[serie.Metric.source.Name][serie.Metric.OriginalName].Dataset,
-					"AVERAGE",
-				)
+				xport.Def(serieTemp, metric.FilePath, metric.Dataset, "AVERAGE")
 			}
 
 			if len(stack) == 0 {
@@ -304,18 +628,24 @@ func (handler *RRDConnectorHandler) rrdGetData(query *GroupQuery, startTime, end
 	// Get plots
 	data := rrd.XportResult{}
 
-	if !infoOnly {
+	if !infoOnly && len(series) > 0 {
 		data, err := xport.Xport(startTime, endTime, step)
 		if err != nil {
 			return nil, err
 		}
 
-		for index, serieName := range data.Legends {
-			result[series[serieName]] = &PlotResult{Info: make(map[string]types.PlotValue)}
+		for index, serieTemp := range data.Legends {
+			serieName := series[serieTemp]
 
+			plots := make([]types.PlotValue, 0, data.RowCnt)
 			for i := 0; i < data.RowCnt; i++ {
-				result[series[serieName]].Plots = append(result[series[serieName]].Plots,
-					types.PlotValue(data.ValueAt(index, i)))
+				plots = append(plots, types.PlotValue(data.ValueAt(index, i)))
+			}
+
+			result[serieName] = &PlotResult{Plots: plots, Info: make(map[string]types.PlotValue)}
+
+			if key, ok := pendingXportKeys[serieName]; ok {
+				handler.xportCache.Set(key, plots)
 			}
 		}
 	}
@@ -379,6 +709,42 @@ func rrdSetGraph(graph *rrd.Grapher, serieName, itemName string, percentiles []f
 	}
 }
 
+// newRRDConnectorHandler builds the state shared by every connector backed by on-disk RRD files (query-plan
+// caches, pooled scratch allocations, and an optional rollup store), so backends other than `rrd' itself — e.g.
+// `collectd', which only supplies its own Update walk — can't forget to initialize a cache/pool the rest of
+// RRDConnectorHandler unconditionally dereferences.
+func newRRDConnectorHandler(origin *Origin, config map[string]string) (*RRDConnectorHandler, error) {
+	handler := &RRDConnectorHandler{
+		Path:    config["path"],
+		Pattern: config["pattern"],
+		// Daemon optionally points at an `rrdcached' UNIX socket (e.g. `unix:/var/run/rrdcached.sock')
+		// so large deployments can offload RRD reads instead of hitting the file-system directly.
+		Daemon:  config["daemon"],
+		origin:  origin,
+		metrics: make(map[string]map[string]*RRDMetric),
+
+		xportCache: newLRUCache(rrdCacheSize),
+		infoCache:  newLRUCache(rrdCacheSize),
+		latency:    &latencyTracker{},
+	}
+
+	handler.stackPool.New = func() interface{} { return make([]string, 0, 8) }
+	handler.seriesPool.New = func() interface{} { return make(map[string]string, 8) }
+
+	if config["rollup_path"] != "" {
+		resolutions, err := parseRollupResolutions(config["rollup_resolutions"])
+		if err != nil {
+			return nil, err
+		}
+
+		handler.rollup = NewRollupStore(config["rollup_path"], resolutions)
+		handler.rollup.Start(0)
+		origin.Rollup = handler.rollup
+	}
+
+	return handler, nil
+}
+
 func init() {
 	ConnectorHandlers["rrd"] = func(origin *Origin, config map[string]string) error {
 		if _, ok := config["path"]; !ok {
@@ -387,13 +753,34 @@ func init() {
 			return fmt.Errorf("missing `pattern' mandatory connector setting")
 		}
 
-		origin.Connector = &RRDConnectorHandler{
-			Path:    config["path"],
-			Pattern: config["pattern"],
-			origin:  origin,
-			metrics: make(map[string]map[string]*RRDMetric),
+		handler, err := newRRDConnectorHandler(origin, config)
+		if err != nil {
+			return err
 		}
 
+		origin.Connector = handler
+
 		return nil
 	}
-}
\ No newline at end of file
+}
+
+// parseRollupResolutions parses a comma-separated list of durations (e.g. "10s,1m,5m") into the resolutions a
+// RollupStore should keep ring buffers at, defaulting to 10s/1m/5m when unset.
+func parseRollupResolutions(raw string) ([]time.Duration, error) {
+	if raw == "" {
+		return []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute}, nil
+	}
+
+	var resolutions []time.Duration
+
+	for _, chunk := range strings.Split(raw, ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(chunk))
+		if err != nil {
+			return nil, fmt.Errorf("invalid `rollup_resolutions' entry `%s': %s", chunk, err)
+		}
+
+		resolutions = append(resolutions, duration)
+	}
+
+	return resolutions, nil
+}
@@ -0,0 +1,230 @@
+package connector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/facette/facette/pkg/types"
+	"github.com/facette/facette/thirdparty/github.com/ziutek/rrd"
+)
+
+// BatchConnectorHandler is implemented by connectors that can serve several plot requests through a single
+// underlying round-trip (e.g. one rrd.Exporter/rrd.Grapher invocation covering many Def/XportDef entries).
+// QueryPlan uses it when available and falls back to issuing GetPlots once per request otherwise.
+type BatchConnectorHandler interface {
+	GetPlotsBatch(queries []*PlotQuery) ([]map[string]*PlotResult, error)
+}
+
+// QueryPlan coalesces the plot requests gathered while rendering a single page (e.g. a dashboard with dozens of
+// graphs) into as few connector round-trips as possible. Requests are grouped by origin, so a dashboard whose
+// graphs all come from the same origin gets a single batched call regardless of how many graphs it has.
+type QueryPlan struct {
+	queries []planQuery
+}
+
+type planQuery struct {
+	origin *Origin
+	query  *PlotQuery
+}
+
+// NewQueryPlan creates an empty, request-scoped QueryPlan.
+func NewQueryPlan() *QueryPlan {
+	return &QueryPlan{}
+}
+
+// Add queues a plot request against origin, returning the index its result will be found at once Execute
+// returns.
+func (plan *QueryPlan) Add(origin *Origin, query *PlotQuery) int {
+	plan.queries = append(plan.queries, planQuery{origin: origin, query: query})
+	return len(plan.queries) - 1
+}
+
+// Execute runs every queued request, batching consecutive requests that share both an origin and a
+// BatchConnectorHandler-capable connector into a single call. Results are returned in the order they were
+// added.
+func (plan *QueryPlan) Execute() ([]map[string]*PlotResult, error) {
+	results := make([]map[string]*PlotResult, len(plan.queries))
+
+	index := 0
+
+	for index < len(plan.queries) {
+		origin := plan.queries[index].origin
+
+		batcher, ok := origin.Connector.(BatchConnectorHandler)
+		if !ok {
+			result, err := origin.Connector.GetPlots(plan.queries[index].query.Group, plan.queries[index].query.StartTime,
+				plan.queries[index].query.EndTime, plan.queries[index].query.Step, plan.queries[index].query.Percentiles)
+			if err != nil {
+				return nil, err
+			}
+
+			results[index] = result
+			index++
+
+			continue
+		}
+
+		// Gather the run of consecutive requests sharing this origin so they can be issued as one batch.
+		start := index
+		group := []*PlotQuery{}
+
+		for index < len(plan.queries) && plan.queries[index].origin == origin {
+			group = append(group, plan.queries[index].query)
+			index++
+		}
+
+		batched, err := batcher.GetPlotsBatch(group)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, result := range batched {
+			results[start+i] = result
+		}
+	}
+
+	return results, nil
+}
+
+// GetPlotsBatch implements BatchConnectorHandler for the RRD connector: it builds a single rrd.Grapher and
+// rrd.Exporter covering every queued query's series (RRD supports many Def/XportDef entries per call), then
+// splits the combined result back out per query.
+func (handler *RRDConnectorHandler) GetPlotsBatch(queries []*PlotQuery) ([]map[string]*PlotResult, error) {
+	results := make([]map[string]*PlotResult, len(queries))
+
+	// A single Graph/Xport call only covers one time window, so queries that disagree on start/end/step are
+	// executed on their own rather than corrupting a shared batch. The batch only plots each serie on its own, so
+	// queries that aggregate their series (avg/sum) or apply a group-level scale are executed on their own too.
+	pending := []int{}
+
+	for i, query := range queries {
+		if query.Group.Type != OperGroupTypeNone || query.Group.Scale != 0 ||
+			i > 0 && (!query.StartTime.Equal(queries[0].StartTime) || !query.EndTime.Equal(queries[0].EndTime) ||
+				query.Step != queries[0].Step) {
+
+			result, err := handler.GetPlots(query.Group, query.StartTime, query.EndTime, query.Step, query.Percentiles)
+			if err != nil {
+				return nil, err
+			}
+
+			results[i] = result
+
+			continue
+		}
+
+		pending = append(pending, i)
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	first := queries[pending[0]]
+
+	start := time.Now()
+
+	graph := rrd.NewGrapher()
+	xport := rrd.NewExporter()
+
+	if handler.Daemon != "" {
+		graph.SetDaemon(handler.Daemon)
+		xport.SetDaemon(handler.Daemon)
+	}
+
+	series := make(map[string]string)
+
+	for _, i := range pending {
+		query := queries[i]
+
+		for s, serie := range query.Group.Series {
+			if serie.Metric == nil {
+				continue
+			}
+
+			serieTemp := fmt.Sprintf("q%d-serie%d", i, s)
+
+			metric, ok := handler.getMetric(serie.Metric.source.Name, serie.Metric.OriginalName)
+			if !ok {
+				continue
+			}
+
+			graph.Def(serieTemp+"-orig0", metric.FilePath, metric.Dataset, "AVERAGE")
+			xport.Def(serieTemp+"-orig0", metric.FilePath, metric.Dataset, "AVERAGE")
+
+			if serie.Scale != 0 {
+				graph.CDef(serieTemp, fmt.Sprintf("%s-orig0,%f,*", serieTemp, serie.Scale))
+				xport.CDef(serieTemp, fmt.Sprintf("%s-orig0,%f,*", serieTemp, serie.Scale))
+			} else {
+				graph.CDef(serieTemp, serieTemp+"-orig0")
+				xport.CDef(serieTemp, serieTemp+"-orig0")
+			}
+
+			// Label graph Print items with the batch-unique serieTemp rather than serie.Name: two distinct
+			// queries coalesced into the same batch may share a serie name (e.g. both named "cpu"), and
+			// rrdParseInfo keys combinedInfo by that label, so a shared name would collapse their rows onto
+			// the same entry.
+			rrdSetGraph(graph, serieTemp, serieTemp, query.Percentiles)
+			xport.XportDef(serieTemp, serieTemp)
+
+			series[serieTemp] = serie.Name
+		}
+	}
+
+	data, err := xport.Xport(first.StartTime, first.EndTime, first.Step)
+	if err != nil {
+		return nil, err
+	}
+
+	byQuery := make([]map[string]*PlotResult, len(queries))
+	for _, i := range pending {
+		byQuery[i] = make(map[string]*PlotResult)
+	}
+
+	for index, serieTemp := range data.Legends {
+		var queryIndex int
+		var serie int
+
+		if _, err := fmt.Sscanf(serieTemp, "q%d-serie%d", &queryIndex, &serie); err != nil {
+			continue
+		}
+
+		plotResult := &PlotResult{Info: make(map[string]types.PlotValue)}
+
+		for i := 0; i < data.RowCnt; i++ {
+			plotResult.Plots = append(plotResult.Plots, types.PlotValue(data.ValueAt(index, i)))
+		}
+
+		byQuery[queryIndex][series[serieTemp]] = plotResult
+	}
+
+	graphInfo, _, err := graph.Graph(first.StartTime, first.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	combinedInfo := make(map[string]*PlotResult)
+	rrdParseInfo(graphInfo, combinedInfo)
+
+	for _, i := range pending {
+		for serieTemp, serieName := range series {
+			var queryIndex, serie int
+			if _, err := fmt.Sscanf(serieTemp, "q%d-serie%d", &queryIndex, &serie); err != nil || queryIndex != i {
+				continue
+			}
+
+			// combinedInfo is keyed by the batch-unique serieTemp (see the rrdSetGraph call above), so
+			// re-key it back to serieName when splitting the result out for this query.
+			if info, ok := combinedInfo[serieTemp]; ok && byQuery[i][serieName] != nil {
+				byQuery[i][serieName].Info = info.Info
+			}
+		}
+
+		results[i] = byQuery[i]
+	}
+
+	data.FreeValues()
+
+	handler.latency.Record(time.Since(start))
+
+	return results, nil
+}
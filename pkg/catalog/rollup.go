@@ -0,0 +1,361 @@
+package connector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/facette/facette/pkg/types"
+)
+
+// rollupFlushInterval is how often RollupStore.Start persists ring buffers to their on-disk chunk files.
+const rollupFlushInterval = time.Minute
+
+// RollupFunc identifies the aggregation applied when querying rolled-up samples.
+type RollupFunc string
+
+// Supported RollupFunc values.
+const (
+	RollupCount RollupFunc = "count"
+	RollupSum   RollupFunc = "sum"
+	RollupRate  RollupFunc = "rate"
+	RollupAvg   RollupFunc = "avg"
+)
+
+// rollupBufferSize is the number of samples kept in memory per ring buffer before the oldest ones are
+// overwritten; combined with a resolution this bounds how far back a query can reach. Query reads only these
+// in-memory buffers; the on-disk chunk files are written by flushTo but never read back.
+const rollupBufferSize = 4096
+
+// rollupSample represents one count/sum-over-time bucket. seq is the monotonically increasing bucket number it
+// was created at, used by flushTo to tell which samples haven't been persisted to disk yet.
+type rollupSample struct {
+	time  time.Time
+	count uint64
+	sum   float64
+	seq   uint64
+}
+
+// ringBuffer is a fixed-size, resolution-aligned circular buffer of rollupSamples.
+type ringBuffer struct {
+	resolution time.Duration
+	samples    []rollupSample
+	pos        int
+	full       bool
+
+	seq        uint64 // total number of buckets ever created
+	flushedSeq uint64 // highest bucket seq already persisted by flushTo
+}
+
+func newRingBuffer(resolution time.Duration) *ringBuffer {
+	return &ringBuffer{resolution: resolution, samples: make([]rollupSample, rollupBufferSize)}
+}
+
+// align rounds t down to the resolution boundary its bucket belongs to.
+func (rb *ringBuffer) align(t time.Time) time.Time {
+	return t.Truncate(rb.resolution)
+}
+
+// add records value at t, accumulating into the existing bucket when t falls within the current one.
+func (rb *ringBuffer) add(t time.Time, value float64) {
+	bucket := rb.align(t)
+
+	current := &rb.samples[rb.pos]
+	if current.count > 0 && current.time.Equal(bucket) {
+		current.count++
+		current.sum += value
+		return
+	}
+
+	rb.pos = (rb.pos + 1) % len(rb.samples)
+	if rb.pos == 0 {
+		rb.full = true
+	}
+
+	rb.seq++
+	rb.samples[rb.pos] = rollupSample{time: bucket, count: 1, sum: value, seq: rb.seq}
+}
+
+// ordered returns the in-memory samples between start and end, oldest first.
+func (rb *ringBuffer) ordered(start, end time.Time) []rollupSample {
+	n := rb.pos + 1
+	if rb.full {
+		n = len(rb.samples)
+	}
+
+	result := make([]rollupSample, 0, n)
+
+	for i := 0; i < n; i++ {
+		index := (rb.pos - i + len(rb.samples)) % len(rb.samples)
+
+		sample := rb.samples[index]
+		if sample.count == 0 {
+			continue
+		}
+
+		if sample.time.Before(start) || sample.time.After(end) {
+			continue
+		}
+
+		result = append(result, sample)
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return result
+}
+
+// unflushed returns the completed buckets added since the last flushTo call, oldest first, excluding the bucket
+// still being accumulated at rb.pos (which may still receive more samples), along with the seq to record as
+// flushed once they've been durably written.
+func (rb *ringBuffer) unflushed() ([]rollupSample, uint64) {
+	n := rb.pos
+	if rb.full {
+		n = len(rb.samples) - 1
+	}
+
+	result := make([]rollupSample, 0, n)
+
+	for i := 1; i <= n; i++ {
+		index := (rb.pos - i + len(rb.samples)) % len(rb.samples)
+
+		sample := rb.samples[index]
+		if sample.count == 0 || sample.seq <= rb.flushedSeq {
+			continue
+		}
+
+		result = append(result, sample)
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	flushedSeq := rb.flushedSeq
+	if len(result) > 0 {
+		flushedSeq = result[len(result)-1].seq
+	}
+
+	return result, flushedSeq
+}
+
+// flushTo appends the samples added since the last flush to the chunk file at path, in a compact fixed-size
+// binary record: an int64 Unix-nanosecond timestamp, a uint64 count and a float64 sum, 24 bytes per sample.
+func (rb *ringBuffer) flushTo(path string) error {
+	samples, flushedSeq := rb.unflushed()
+	if len(samples) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, sample := range samples {
+		if err := binary.Write(file, binary.LittleEndian, sample.time.UnixNano()); err != nil {
+			return err
+		}
+
+		if err := binary.Write(file, binary.LittleEndian, sample.count); err != nil {
+			return err
+		}
+
+		if err := binary.Write(file, binary.LittleEndian, sample.sum); err != nil {
+			return err
+		}
+	}
+
+	rb.flushedSeq = flushedSeq
+
+	return nil
+}
+
+// RollupStore maintains in-memory ring buffers of count/sum-over-time samples for the metrics it is fed, at a
+// fixed set of resolutions, periodically flushed to a compact on-disk chunk file per source/metric pair.
+type RollupStore struct {
+	Dir         string
+	Resolutions []time.Duration
+
+	mu       sync.Mutex
+	buffers  map[string]map[time.Duration]*ringBuffer
+	stopChan chan struct{}
+}
+
+// NewRollupStore creates a RollupStore flushing its chunk files under dir, keeping ring buffers at each of the
+// given resolutions.
+func NewRollupStore(dir string, resolutions []time.Duration) *RollupStore {
+	return &RollupStore{
+		Dir:         dir,
+		Resolutions: resolutions,
+		buffers:     make(map[string]map[time.Duration]*ringBuffer),
+	}
+}
+
+func rollupKey(source, metric string) string {
+	return source + "/" + metric
+}
+
+func (store *RollupStore) chunkPath(source, metric string, resolution time.Duration) string {
+	return filepath.Join(store.Dir, fmt.Sprintf("%s_%s_%s.chunk", source, metric, resolution))
+}
+
+// Record accumulates a single sample for source/metric at t into every tracked resolution.
+func (store *RollupStore) Record(source, metric string, t time.Time, value float64) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key := rollupKey(source, metric)
+
+	if _, ok := store.buffers[key]; !ok {
+		store.buffers[key] = make(map[time.Duration]*ringBuffer)
+
+		for _, resolution := range store.Resolutions {
+			store.buffers[key][resolution] = newRingBuffer(resolution)
+		}
+	}
+
+	for _, rb := range store.buffers[key] {
+		rb.add(t, value)
+	}
+}
+
+// Flush persists every in-memory ring buffer to its on-disk chunk file.
+func (store *RollupStore) Flush() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if err := os.MkdirAll(store.Dir, 0755); err != nil {
+		return err
+	}
+
+	for key, resBuffers := range store.buffers {
+		source, metric := splitRollupKey(key)
+
+		for resolution, rb := range resBuffers {
+			path := store.chunkPath(source, metric, resolution)
+
+			// Metric names may contain `/' (e.g. `<metric>/<dataset>'), placing the chunk file in a subdirectory.
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+
+			if err := rb.flushTo(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Start begins periodically flushing the store's ring buffers to their on-disk chunk files every interval,
+// returning immediately; interval defaults to rollupFlushInterval when zero. Call Stop to terminate the
+// background loop once the connector holding the store is closed.
+func (store *RollupStore) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = rollupFlushInterval
+	}
+
+	store.stopChan = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := store.Flush(); err != nil {
+					log.Println("ERROR: rollup flush: " + err.Error())
+				}
+			case <-store.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the background flush loop started by Start.
+func (store *RollupStore) Stop() {
+	if store.stopChan != nil {
+		close(store.stopChan)
+	}
+}
+
+func splitRollupKey(key string) (string, string) {
+	for i := 0; i < len(key); i++ {
+		if key[i] == '/' {
+			return key[:i], key[i+1:]
+		}
+	}
+
+	return key, ""
+}
+
+// HasResolution reports whether the store keeps a ring buffer at exactly step granularity for source/metric.
+func (store *RollupStore) HasResolution(source, metric string, step time.Duration) bool {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	resBuffers, ok := store.buffers[rollupKey(source, metric)]
+	if !ok {
+		return false
+	}
+
+	_, ok = resBuffers[step]
+
+	return ok
+}
+
+// Query aggregates the samples recorded for source/metric between start and end into step-sized plots, applying
+// fn to each bucket. It only serves requests whose step exactly matches one of the store's resolutions; callers
+// should fall back to a full-resolution source (e.g. RRD xport) otherwise.
+func (store *RollupStore) Query(source, metric string, fn RollupFunc, start, end time.Time,
+	step time.Duration) ([]types.PlotValue, error) {
+
+	store.mu.Lock()
+
+	rb, ok := store.buffers[rollupKey(source, metric)][step]
+	if !ok {
+		store.mu.Unlock()
+		return nil, fmt.Errorf("no rollup at step `%s' for `%s/%s'", step, source, metric)
+	}
+
+	// rb.ordered reads samples/pos/full, which Record mutates under store.mu; it must stay under the lock
+	// rather than being read after an intervening Unlock.
+	samples := rb.ordered(start, end)
+
+	store.mu.Unlock()
+
+	plots := make([]types.PlotValue, 0, len(samples))
+
+	for _, sample := range samples {
+		switch fn {
+		case RollupCount:
+			plots = append(plots, types.PlotValue(sample.count))
+		case RollupSum:
+			plots = append(plots, types.PlotValue(sample.sum))
+		case RollupAvg:
+			plots = append(plots, types.PlotValue(sample.sum/float64(sample.count)))
+		case RollupRate:
+			plots = append(plots, types.PlotValue(sample.sum/step.Seconds()))
+		default:
+			return nil, fmt.Errorf("unknown rollup function `%s'", fn)
+		}
+	}
+
+	if len(plots) == 0 {
+		plots = append(plots, types.PlotValue(math.NaN()))
+	}
+
+	return plots, nil
+}
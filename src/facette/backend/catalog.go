@@ -4,7 +4,12 @@ import (
 	"facette/config"
 	"fmt"
 	"log"
+	"net/http"
+	"sync"
 	"time"
+
+	connector "github.com/facette/facette/pkg/catalog"
+	"github.com/facette/facette/pkg/types"
 )
 
 // Catalog represents the main structure of running Facette's instance (e.g. origins, sources, metrics).
@@ -13,6 +18,11 @@ type Catalog struct {
 	Origins    map[string]*Origin
 	Updated    time.Time
 	debugLevel int
+
+	mu              sync.RWMutex
+	stopChan        chan struct{}
+	updateTimes     map[string]time.Duration
+	profilingServer *http.Server
 }
 
 // AddOrigin adds a new Origin entry into the Catalog instance.
@@ -34,14 +44,19 @@ func (catalog *Catalog) AddOrigin(name string, config map[string]string) (*Origi
 		return nil, err
 	}
 
+	catalog.mu.Lock()
 	catalog.Origins[name] = origin
+	catalog.mu.Unlock()
 
 	return origin, nil
 }
 
 // GetMetric returns an existing Metric entry based on its origin, source and name.
 func (catalog *Catalog) GetMetric(origin, source, name string) *Metric {
-	if !catalog.MetricExists(origin, source, name) {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	if !catalog.metricExists(origin, source, name) {
 		return nil
 	}
 
@@ -50,6 +65,14 @@ func (catalog *Catalog) GetMetric(origin, source, name string) *Metric {
 
 // MetricExists returns whether a metric exists or not.
 func (catalog *Catalog) MetricExists(origin, source, name string) bool {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	return catalog.metricExists(origin, source, name)
+}
+
+// metricExists is the lock-free core of MetricExists; callers must already hold catalog.mu.
+func (catalog *Catalog) metricExists(origin, source, name string) bool {
 	if _, ok := catalog.Origins[origin]; ok {
 		if _, ok := catalog.Origins[origin].Sources[source]; ok {
 			if _, ok := catalog.Origins[origin].Sources[source].Metrics[name]; ok {
@@ -61,6 +84,72 @@ func (catalog *Catalog) MetricExists(origin, source, name string) bool {
 	return false
 }
 
+// AddMetric registers a single metric for an origin/source pair without rebuilding the whole origin. Connectors
+// use this fast path (relayed through the origin's delta channel) to report metrics discovered outside of a
+// full Update, e.g. by a file-system watcher.
+func (catalog *Catalog) AddMetric(origin, source, name string) {
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+
+	o, ok := catalog.Origins[origin]
+	if !ok {
+		return
+	}
+
+	o.addMetric(source, name)
+}
+
+// RemoveMetric unregisters a single metric for an origin/source pair without rebuilding the whole origin.
+func (catalog *Catalog) RemoveMetric(origin, source, name string) {
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+
+	o, ok := catalog.Origins[origin]
+	if !ok {
+		return
+	}
+
+	o.removeMetric(source, name)
+}
+
+// GetRollup returns pre-aggregated plot data for a single metric from its origin's rollup store, without
+// going through the origin's connector. It returns an error if the origin has no rollup store configured, or
+// none of its ring buffers matches step exactly.
+func (catalog *Catalog) GetRollup(origin, source, name string, fn connector.RollupFunc, start, end time.Time,
+	step time.Duration) ([]types.PlotValue, error) {
+
+	catalog.mu.RLock()
+	o, ok := catalog.Origins[origin]
+	catalog.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown `%s' origin", origin)
+	}
+
+	if o.connector.Rollup == nil {
+		return nil, fmt.Errorf("origin `%s' has no rollup store configured", origin)
+	}
+
+	return o.connector.Rollup.Query(source, name, fn, start, end, step)
+}
+
+// Stats reports query-cache effectiveness and latency for every origin whose connector tracks it (currently
+// the RRD connector, and the Collectd connector built on it). Origins whose connector doesn't implement connector.StatsReporter are omitted.
+func (catalog *Catalog) Stats() map[string]connector.Stats {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	stats := make(map[string]connector.Stats)
+
+	for name, origin := range catalog.Origins {
+		if reporter, ok := origin.connector.Connector.(connector.StatsReporter); ok {
+			stats[name] = reporter.Stats()
+		}
+	}
+
+	return stats
+}
+
 // Update updates the current Catalog by updating its origins.
 func (catalog *Catalog) Update() error {
 	var (
@@ -72,14 +161,32 @@ func (catalog *Catalog) Update() error {
 
 	log.Println("INFO: catalog update started")
 
-	// Update catalog origins
+	catalog.mu.RLock()
+	origins := make([]*Origin, 0, len(catalog.Origins))
 	for _, origin := range catalog.Origins {
-		if err = origin.Update(); err != nil {
+		origins = append(origins, origin)
+	}
+	catalog.mu.RUnlock()
+
+	// Update catalog origins
+	updateTimes := make(map[string]time.Duration, len(origins))
+
+	for _, origin := range origins {
+		start := time.Now()
+		updateErr := origin.Update()
+		updateTimes[origin.Name] = time.Since(start)
+
+		if updateErr != nil {
+			err = updateErr
 			log.Println("ERROR: " + err.Error())
 			success = false
 		}
 	}
 
+	catalog.mu.Lock()
+	catalog.updateTimes = updateTimes
+	catalog.mu.Unlock()
+
 	// Handle output information
 	if !success {
 		log.Println("INFO: catalog update failed")
@@ -92,8 +199,66 @@ func (catalog *Catalog) Update() error {
 	return nil
 }
 
+// Start launches the per-origin background refresh scheduler, so large catalogs no longer need a full Update to
+// pick up newly appearing metrics.
+func (catalog *Catalog) Start() {
+	catalog.stopChan = make(chan struct{})
+
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	for _, origin := range catalog.Origins {
+		go catalog.scheduleOrigin(origin)
+	}
+}
+
+// Stop terminates the background refresh scheduler started by Start.
+func (catalog *Catalog) Stop() {
+	if catalog.stopChan != nil {
+		close(catalog.stopChan)
+	}
+}
+
+// scheduleOrigin runs origin's periodic Refresh on its configured interval, while continuously draining the
+// per-metric deltas its connector pushes between refreshes (e.g. from a file-system watcher).
+func (catalog *Catalog) scheduleOrigin(origin *Origin) {
+	interval := origin.RefreshInterval
+	if interval == 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	go func() {
+		for {
+			select {
+			case delta, ok := <-origin.connector.Deltas():
+				if !ok {
+					return
+				}
+
+				catalog.AddMetric(origin.Name, delta[0], delta[1])
+			case <-catalog.stopChan:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := origin.Refresh(); err != nil {
+				log.Println("ERROR: " + err.Error())
+			}
+		case <-catalog.stopChan:
+			return
+		}
+	}
+}
+
 // NewCatalog creates a new instance of Catalog.
 func NewCatalog(config *config.Config, debugLevel int) *Catalog {
 	// Create new Catalog instance
 	return &Catalog{Config: config, Origins: make(map[string]*Origin), debugLevel: debugLevel}
-}
\ No newline at end of file
+}
@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"time"
+
+	connector "github.com/facette/facette/pkg/catalog"
+)
+
+// DefaultRefreshInterval is the refresh interval applied to an origin that does not set its own
+// `refresh_interval' connector setting.
+const DefaultRefreshInterval = 5 * time.Minute
+
+// Origin represents a backend origin, combining a registered connector with the sources/metrics derived from it.
+type Origin struct {
+	Name            string
+	Sources         map[string]*Source
+	RefreshInterval time.Duration
+
+	catalog   *Catalog
+	connector *connector.Origin
+}
+
+// Source represents a single source (e.g. a host) reporting metrics within an Origin.
+type Source struct {
+	Name    string
+	Metrics map[string]*Metric
+	origin  *Origin
+}
+
+// Metric represents a single metric tracked for a Source.
+type Metric struct {
+	Name         string
+	OriginalName string
+	source       *Source
+}
+
+// BackendHandlers stores the registered backend factories, indexed by their `type' configuration key.
+var BackendHandlers = make(map[string]func(origin *Origin, config map[string]string) error)
+
+// addMetric registers a single source/metric pair, creating the Source entry if necessary. It is not safe for
+// concurrent use on its own; callers must hold the owning Catalog's lock.
+func (origin *Origin) addMetric(source, name string) {
+	addMetricTo(origin.Sources, origin, source, name)
+}
+
+// addMetricTo registers a single source/metric pair into sources, creating the Source entry if necessary.
+// Unlike addMetric, it does not touch origin.Sources itself, so Update can build a full rebuild into a private
+// map and only take the Catalog's lock once, to swap it in.
+func addMetricTo(sources map[string]*Source, origin *Origin, source, name string) {
+	src, ok := sources[source]
+	if !ok {
+		src = &Source{Name: source, Metrics: make(map[string]*Metric), origin: origin}
+		sources[source] = src
+	}
+
+	src.Metrics[name] = &Metric{Name: name, OriginalName: name, source: src}
+}
+
+// removeMetric unregisters a single source/metric pair, pruning the Source entry once it has no metric left. It
+// is not safe for concurrent use on its own; callers must hold the owning Catalog's lock.
+func (origin *Origin) removeMetric(source, name string) {
+	src, ok := origin.Sources[source]
+	if !ok {
+		return
+	}
+
+	delete(src.Metrics, name)
+
+	if len(src.Metrics) == 0 {
+		delete(origin.Sources, source)
+	}
+}
+
+// Update triggers a full update of the origin, rebuilding its Sources/Metrics from its connector. The rebuild is
+// assembled into a private map and only swapped into origin.Sources once complete, under the owning Catalog's
+// lock, so concurrent lookups (Catalog.GetMetric/MetricExists) never observe a partially rebuilt origin.
+func (origin *Origin) Update() error {
+	sources := make(map[string]*Source)
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- origin.connector.Connector.Update() }()
+
+	for delta := range origin.connector.Discoveries() {
+		addMetricTo(sources, origin, delta[0], delta[1])
+	}
+
+	origin.catalog.mu.Lock()
+	origin.Sources = sources
+	origin.catalog.mu.Unlock()
+
+	return <-errChan
+}
+
+// Refresh triggers an incremental update of the origin through its connector.
+func (origin *Origin) Refresh() error {
+	return origin.connector.Connector.Refresh()
+}
+
+// Connector returns the underlying catalog.Origin, for callers (e.g. a connector.QueryPlan) that need to reach
+// past the backend's Sources/Metrics view down to the registered connector itself.
+func (origin *Origin) Connector() *connector.Origin {
+	return origin.connector
+}
+
+// bindConnector registers a BackendHandlers factory for name, bridging it to the matching connector.ConnectorHandlers
+// entry so every registered connector is automatically usable as an origin `type'.
+func bindConnector(name string) {
+	BackendHandlers[name] = func(origin *Origin, config map[string]string) error {
+		connOrigin := connector.NewOrigin(origin.Name)
+
+		if err := connector.ConnectorHandlers[name](connOrigin, config); err != nil {
+			return err
+		}
+
+		origin.connector = connOrigin
+
+		if interval, ok := config["refresh_interval"]; ok {
+			parsed, err := time.ParseDuration(interval)
+			if err != nil {
+				return err
+			}
+
+			origin.RefreshInterval = parsed
+		}
+
+		return nil
+	}
+}
+
+func init() {
+	for name := range connector.ConnectorHandlers {
+		bindConnector(name)
+	}
+}
@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	// net/http/pprof registers its handlers on http.DefaultServeMux as a side effect of being imported, not
+	// on the mux built in StartProfiling below. That registration happens regardless of whether
+	// ProfilingListen is set, so pprof becomes reachable on ANY listener the server binary happens to serve
+	// off http.DefaultServeMux (e.g. a stray http.ListenAndServe(addr, nil)). Gating pprof behind
+	// ProfilingListen only works as long as nothing else in the binary serves off the default mux.
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	connector "github.com/facette/facette/pkg/catalog"
+)
+
+// CatalogStats is the JSON-marshalable snapshot of a Catalog published under the `facette_catalog' key on
+// /debug/vars, for diagnosing Catalog.Update walk performance and RRD xport hotspots in production.
+type CatalogStats struct {
+	Origins int `json:"origins"`
+	Sources int `json:"sources"`
+	Metrics int `json:"metrics"`
+
+	Updated     time.Time                  `json:"updated"`
+	UpdateTimes map[string]time.Duration   `json:"update_times"`
+	Connectors  map[string]connector.Stats `json:"connectors"`
+}
+
+// stats returns a point-in-time snapshot of the catalog's size and connector query-cache effectiveness.
+func (catalog *Catalog) stats() CatalogStats {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	stats := CatalogStats{
+		Origins:     len(catalog.Origins),
+		Updated:     catalog.Updated,
+		UpdateTimes: catalog.updateTimes,
+	}
+
+	for _, origin := range catalog.Origins {
+		stats.Sources += len(origin.Sources)
+
+		for _, source := range origin.Sources {
+			stats.Metrics += len(source.Metrics)
+		}
+	}
+
+	stats.Connectors = catalog.Stats()
+
+	return stats
+}
+
+// StartProfiling starts the admin profiling HTTP mux on catalog.Config.ProfilingListen, exposing net/http/pprof
+// under /debug/pprof/ and a /debug/vars expvar endpoint reporting CatalogStats. It is a no-op when
+// ProfilingListen is empty, so operators opt in by setting it (typically to a localhost address, since the mux
+// is unauthenticated). It is safe to call only once per Catalog; calling it again while already running returns
+// an error.
+func (catalog *Catalog) StartProfiling() error {
+	if catalog.Config.ProfilingListen == "" {
+		return nil
+	}
+
+	if catalog.profilingServer != nil {
+		return fmt.Errorf("profiling server already started")
+	}
+
+	catalog.publishExpvar()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	server := &http.Server{Addr: catalog.Config.ProfilingListen, Handler: mux}
+	catalog.profilingServer = server
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Println("ERROR: profiling server: " + err.Error())
+		}
+	}()
+
+	log.Println("INFO: profiling server listening on " + catalog.Config.ProfilingListen)
+
+	return nil
+}
+
+// StopProfiling shuts down the admin profiling HTTP mux started by StartProfiling. It is a no-op if profiling
+// was never started.
+func (catalog *Catalog) StopProfiling() error {
+	if catalog.profilingServer == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := catalog.profilingServer.Shutdown(ctx)
+	catalog.profilingServer = nil
+
+	return err
+}
+
+// expvarOnce guards against expvar.Publish panicking on a duplicate key, since a process is only expected to
+// run a single Catalog with profiling enabled.
+var expvarOnce sync.Once
+
+// publishExpvar registers the `facette_catalog' expvar once per process.
+func (catalog *Catalog) publishExpvar() {
+	expvarOnce.Do(func() {
+		expvar.Publish("facette_catalog", expvar.Func(func() interface{} {
+			return catalog.stats()
+		}))
+	})
+}